@@ -0,0 +1,224 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/openGemini/openGemini/lib/pool"
+)
+
+// defaultAdaptiveSampleWindow is how often AdaptiveZstdPool re-evaluates its
+// level when the caller doesn't specify a window.
+const defaultAdaptiveSampleWindow = 30 * time.Second
+
+// defaultAdaptiveLatencyTarget is the p99 encode latency AdaptiveZstdPool
+// tries to stay under before it stops climbing to higher (slower) levels.
+const defaultAdaptiveLatencyTarget = 20 * time.Millisecond
+
+// highHitRatio is the hit-ratio threshold at or above which the pool is
+// considered to have CPU headroom: most encoders are being reused rather
+// than freshly allocated, so the extra cost of a higher compression level
+// is judged affordable.
+const highHitRatio = 0.8
+
+// AdaptiveZstdPool is a zstd.Encoder pool that periodically picks a
+// compression level within [min, max] based on telemetry recorded through
+// its HitRatioHook: it climbs a level when the pool's hit ratio is high
+// (little allocation pressure) and p99 encode latency has margin against
+// AdaptiveZstdPool's latency target, and drops a level when that latency
+// target is exceeded.
+type AdaptiveZstdPool struct {
+	min, max      int
+	level         int32 // atomic, current zstd.EncoderLevel value
+	sampleWindow  time.Duration
+	latencyTarget time.Duration
+
+	hook *pool.HitRatioHook
+
+	total, hit int64 // atomic, lifetime cumulative; mirrors what's reported through hook and Stats
+
+	// windowTotal/windowHit mirror total/hit but are zeroed by maybeAdjust
+	// every sampleWindow, so the hill-climbing rule reacts to how the pool
+	// has behaved recently rather than being diluted by its entire history
+	// once it's been running a while.
+	windowTotal, windowHit int64 // atomic
+
+	mu         sync.Mutex
+	pools      map[int]*sync.Pool
+	lastAdjust time.Time
+}
+
+// NewAdaptiveZstdPool creates an AdaptiveZstdPool whose level ranges over
+// [min, max] (values from github.com/klauspost/compress/zstd's
+// EncoderLevel), starting at min and re-evaluated every sampleWindow.
+// sampleWindow <= 0 uses defaultAdaptiveSampleWindow.
+func NewAdaptiveZstdPool(min, max int, sampleWindow time.Duration) *AdaptiveZstdPool {
+	if sampleWindow <= 0 {
+		sampleWindow = defaultAdaptiveSampleWindow
+	}
+	p := &AdaptiveZstdPool{
+		min:           min,
+		max:           max,
+		level:         int32(min),
+		sampleWindow:  sampleWindow,
+		latencyTarget: defaultAdaptiveLatencyTarget,
+		pools:         make(map[int]*sync.Pool),
+		lastAdjust:    time.Now(),
+	}
+	p.hook = pool.NewHitRatioHook(
+		func(n int64) { atomic.AddInt64(&p.total, n) },
+		func(n int64) { atomic.AddInt64(&p.hit, n) },
+	)
+	return p
+}
+
+// SetLatencyTarget overrides the default p99 encode latency target used by
+// the hill-climbing rule.
+func (p *AdaptiveZstdPool) SetLatencyTarget(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latencyTarget = d
+}
+
+// Level returns the compression level currently in use.
+func (p *AdaptiveZstdPool) Level() int {
+	return int(atomic.LoadInt32(&p.level))
+}
+
+func (p *AdaptiveZstdPool) poolForLevel(level int) *sync.Pool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ep, ok := p.pools[level]
+	if !ok {
+		ep = &sync.Pool{}
+		p.pools[level] = ep
+	}
+	return ep
+}
+
+// Get returns a zstd.Encoder reset to write to w, along with the level it
+// was built at. Callers must pass that level back to Put unchanged: the
+// level the encoder actually used travels with the return value instead of
+// being re-derived from a second, independently-racy Level() call, so a
+// level change between Get and Put can never mislevel the encoder Put
+// stores.
+func (p *AdaptiveZstdPool) Get(w io.Writer) (*zstd.Encoder, int) {
+	p.hook.IncrTotal(1)
+	atomic.AddInt64(&p.windowTotal, 1)
+
+	level := p.Level()
+	ep := p.poolForLevel(level)
+
+	v := ep.Get()
+	if v == nil {
+		zw, _ := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevel(level)))
+		return zw, level
+	}
+	p.hook.IncrHit(1)
+	atomic.AddInt64(&p.windowHit, 1)
+	zw := v.(*zstd.Encoder)
+	zw.Reset(w)
+	return zw, level
+}
+
+// Put closes w and returns it to the pool for the level Get returned
+// alongside it. bytesIn/bytesOut and latency describe the encode that just
+// completed: latency feeds the hill-climbing decision made in maybeAdjust,
+// while bytesIn/bytesOut are only accumulated for Stats and do not affect
+// the level.
+func (p *AdaptiveZstdPool) Put(w *zstd.Encoder, level int, bytesIn, bytesOut int64, latency time.Duration) {
+	_ = w.Close()
+	p.hook.IncrBytes(bytesIn, bytesOut)
+	p.hook.ObserveLatency(latency)
+	p.poolForLevel(level).Put(w)
+	p.maybeAdjust()
+}
+
+// maybeAdjust runs the hill-climbing rule at most once per sampleWindow,
+// against the hit ratio for just the window since its last run: windowTotal
+// and windowHit are drained back to zero here, so a pool that's been
+// running for hours reacts to this window's hit ratio, not one smoothed
+// over its entire lifetime. The p99 latency is taken from the hook's
+// retained samples (the most recent ObserveLatency calls), which are not
+// cleared per window.
+func (p *AdaptiveZstdPool) maybeAdjust() {
+	p.mu.Lock()
+	if time.Since(p.lastAdjust) < p.sampleWindow {
+		p.mu.Unlock()
+		return
+	}
+	p.lastAdjust = time.Now()
+	target := p.latencyTarget
+	p.mu.Unlock()
+
+	total := atomic.SwapInt64(&p.windowTotal, 0)
+	if total == 0 {
+		return
+	}
+	hit := atomic.SwapInt64(&p.windowHit, 0)
+	hitRatio := float64(hit) / float64(total)
+	p99 := p.hook.LatencyPercentile(99)
+
+	level := p.Level()
+	switch {
+	case p99 > target && level > p.min:
+		atomic.AddInt32(&p.level, -1)
+	case p99 < target/2 && hitRatio >= highHitRatio && level < p.max:
+		atomic.AddInt32(&p.level, 1)
+	}
+}
+
+// AdaptiveZstdStats is a snapshot of an AdaptiveZstdPool's telemetry,
+// suitable for scraping by the metrics subsystem.
+type AdaptiveZstdStats struct {
+	Level            int
+	Total            int64
+	Hits             int64
+	HitRatio         float64
+	BytesIn          int64
+	BytesOut         int64
+	CompressionRatio float64
+	LatencyP99       time.Duration
+}
+
+// Stats returns the pool's current level and telemetry.
+func (p *AdaptiveZstdPool) Stats() AdaptiveZstdStats {
+	total := atomic.LoadInt64(&p.total)
+	hit := atomic.LoadInt64(&p.hit)
+	bytesIn, bytesOut := p.hook.Bytes()
+
+	s := AdaptiveZstdStats{
+		Level:      p.Level(),
+		Total:      total,
+		Hits:       hit,
+		BytesIn:    bytesIn,
+		BytesOut:   bytesOut,
+		LatencyP99: p.hook.LatencyPercentile(99),
+	}
+	if total > 0 {
+		s.HitRatio = float64(hit) / float64(total)
+	}
+	if bytesIn > 0 {
+		s.CompressionRatio = float64(bytesOut) / float64(bytesIn)
+	}
+	return s
+}
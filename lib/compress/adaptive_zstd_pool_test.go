@@ -0,0 +1,102 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveZstdPoolGetPutRoundTrip(t *testing.T) {
+	p := NewAdaptiveZstdPool(int(zstd.SpeedFastest), int(zstd.SpeedBestCompression), time.Hour)
+
+	var buf bytes.Buffer
+	writer, level := p.Get(&buf)
+	n, err := writer.Write([]byte("test data"))
+	assert.NoError(t, err)
+	p.Put(writer, level, int64(n), 5, time.Millisecond)
+
+	reader, err := zstd.NewReader(&buf)
+	assert.NoError(t, err)
+	defer reader.Close()
+
+	result := new(bytes.Buffer)
+	_, err = result.ReadFrom(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "test data", result.String())
+}
+
+// feedAdjust drives maybeAdjust's inputs directly (the windowed total/hit
+// counters and observed latency) rather than looping real Get/Put calls,
+// whose hit/miss outcome depends on sync.Pool's GC-driven eviction and so
+// isn't deterministic enough for a unit test.
+func feedAdjust(p *AdaptiveZstdPool, total, hit int64, latency time.Duration) {
+	atomic.StoreInt64(&p.windowTotal, total)
+	atomic.StoreInt64(&p.windowHit, hit)
+	p.hook.ObserveLatency(latency)
+	p.mu.Lock()
+	p.lastAdjust = time.Time{}
+	p.mu.Unlock()
+	p.maybeAdjust()
+}
+
+func TestAdaptiveZstdPoolClimbsOnLowLatencyAndHighHitRatio(t *testing.T) {
+	p := NewAdaptiveZstdPool(int(zstd.SpeedFastest), int(zstd.SpeedBestCompression), time.Hour)
+	p.SetLatencyTarget(time.Second)
+
+	feedAdjust(p, 100, 90, time.Microsecond)
+	assert.Equal(t, int(zstd.SpeedDefault), p.Level())
+
+	feedAdjust(p, 200, 180, time.Microsecond)
+	assert.Equal(t, int(zstd.SpeedBetterCompression), p.Level())
+
+	feedAdjust(p, 300, 270, time.Microsecond)
+	assert.Equal(t, int(zstd.SpeedBestCompression), p.Level())
+
+	// Already at max: another round with the same favorable telemetry must
+	// not push the level past max.
+	feedAdjust(p, 400, 360, time.Microsecond)
+	assert.Equal(t, int(zstd.SpeedBestCompression), p.Level())
+}
+
+func TestAdaptiveZstdPoolDropsOnHighLatency(t *testing.T) {
+	p := NewAdaptiveZstdPool(int(zstd.SpeedFastest), int(zstd.SpeedBestCompression), time.Hour)
+	p.level = int32(zstd.SpeedBestCompression)
+	p.SetLatencyTarget(time.Millisecond)
+
+	feedAdjust(p, 100, 90, 50*time.Millisecond)
+	assert.Equal(t, int(zstd.SpeedBetterCompression), p.Level())
+}
+
+func TestAdaptiveZstdPoolStats(t *testing.T) {
+	p := NewAdaptiveZstdPool(int(zstd.SpeedFastest), int(zstd.SpeedBestCompression), time.Hour)
+
+	var buf bytes.Buffer
+	writer, level := p.Get(&buf)
+	writer.Write([]byte("test data"))
+	p.Put(writer, level, 9, 5, time.Millisecond)
+
+	stats := p.Stats()
+	assert.Equal(t, level, stats.Level)
+	assert.Equal(t, int64(1), stats.Total)
+	assert.Equal(t, int64(9), stats.BytesIn)
+	assert.Equal(t, int64(5), stats.BytesOut)
+	assert.InDelta(t, 5.0/9.0, stats.CompressionRatio, 1e-9)
+}
@@ -0,0 +1,447 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/openGemini/openGemini/lib/pool"
+)
+
+// DefaultChunkedZstdChunkSize is the target size, in uncompressed bytes, of
+// each independently-decompressable frame written by ChunkedZstdWriter when
+// the caller doesn't have a more specific number in mind.
+const DefaultChunkedZstdChunkSize = 512 * 1024
+
+// tocEntryLen is the on-disk size, in bytes, of one chunkTOCEntry.
+const tocEntryLen = 32
+
+// skippableTOCMagic is one of the zstd magic numbers reserved for skippable
+// frames (0x184D2A50-0x184D2A5F); it marks the trailing frame that points a
+// reader at the TOC. See RFC 8878 section 3.1.2.
+const skippableTOCMagic = 0x184D2A5F
+
+// trailerLen is the fixed size of the trailer ChunkedZstdWriter appends
+// after the TOC: an 8-byte skippable-frame header (magic + user data size)
+// followed by a 16-byte payload (TOC offset + TOC length). Because it's
+// fixed-size, a reader locates it with a single seek to size-trailerLen.
+const trailerLen = 8 + 16
+
+// chunkTOCEntry records where one chunk lives in both the logical
+// (uncompressed) and physical (compressed) address spaces.
+type chunkTOCEntry struct {
+	LogicalOffset    int64
+	CompressedOffset int64
+	CompressedLen    int64
+	UncompressedLen  int64
+}
+
+// ChunkedZstdWriter splits a stream into independently-decompressable zstd
+// frames of roughly chunkSize uncompressed bytes each, followed by a TOC
+// mapping logical offsets to the compressed frame that holds them. It is
+// modeled on the zstdchunked format used by eStargz: the TOC lets a reader
+// fetch and decompress only the frames that cover a requested byte range,
+// instead of the whole stream.
+type ChunkedZstdWriter struct {
+	w         io.Writer
+	chunkSize int
+
+	buf        []byte
+	logicalOff int64
+	compOff    int64
+	toc        []chunkTOCEntry
+
+	scratch bytes.Buffer
+}
+
+// NewChunkedZstdWriter creates a ChunkedZstdWriter that writes to w, cutting
+// a new zstd frame roughly every chunkSize uncompressed bytes. A chunkSize
+// <= 0 uses DefaultChunkedZstdChunkSize.
+func NewChunkedZstdWriter(w io.Writer, chunkSize int) *ChunkedZstdWriter {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkedZstdChunkSize
+	}
+	return &ChunkedZstdWriter{
+		w:         w,
+		chunkSize: chunkSize,
+		buf:       chunkScratchPool.Get()[:0],
+	}
+}
+
+// reset prepares cw for reuse against a new destination, as done by
+// GetChunkedZstdWriter. It swaps in a freshly-acquired scratch buffer and
+// returns the old one to chunkScratchPool itself, rather than relying on
+// PutChunkedZstdWriter to do so: at this point cw is exclusively owned by
+// the caller pulling it out of chunkedZstdWriterPool, so this is the only
+// place that can hand buf back to the shared scratch pool without handing
+// the same backing array to two pools — and therefore two goroutines — at
+// once.
+func (cw *ChunkedZstdWriter) reset(w io.Writer, chunkSize int) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkedZstdChunkSize
+	}
+	if cw.buf != nil {
+		chunkScratchPool.Put(cw.buf[:0])
+	}
+	cw.buf = chunkScratchPool.Get()[:0]
+	cw.w = w
+	cw.chunkSize = chunkSize
+	cw.logicalOff = 0
+	cw.compOff = 0
+	cw.toc = cw.toc[:0]
+	cw.scratch.Reset()
+}
+
+// Write buffers p, flushing full chunks to the underlying writer as an
+// independent zstd frame each.
+func (cw *ChunkedZstdWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		n := cw.chunkSize - len(cw.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		cw.buf = append(cw.buf, p[:n]...)
+		p = p[n:]
+
+		if len(cw.buf) >= cw.chunkSize {
+			if err := cw.flushChunk(); err != nil {
+				return total - len(p), err
+			}
+		}
+	}
+	return total, nil
+}
+
+// flushChunk compresses the buffered bytes as a standalone zstd frame,
+// writes it out, and records its TOC entry.
+func (cw *ChunkedZstdWriter) flushChunk() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	cw.scratch.Reset()
+	zw := GetZstdWriter(&cw.scratch)
+	if _, err := zw.Write(cw.buf); err != nil {
+		PutZstdWriter(zw)
+		return err
+	}
+	PutZstdWriter(zw) // Close()s zw, finalizing this frame
+
+	compressedLen := cw.scratch.Len()
+	if _, err := cw.w.Write(cw.scratch.Bytes()); err != nil {
+		return err
+	}
+
+	cw.toc = append(cw.toc, chunkTOCEntry{
+		LogicalOffset:    cw.logicalOff,
+		CompressedOffset: cw.compOff,
+		CompressedLen:    int64(compressedLen),
+		UncompressedLen:  int64(len(cw.buf)),
+	})
+	cw.logicalOff += int64(len(cw.buf))
+	cw.compOff += int64(compressedLen)
+	cw.buf = cw.buf[:0]
+	return nil
+}
+
+// Close flushes any buffered tail as a final chunk, then writes the TOC and
+// its trailing skippable-frame pointer. It does not close the underlying
+// writer.
+func (cw *ChunkedZstdWriter) Close() error {
+	if err := cw.flushChunk(); err != nil {
+		return err
+	}
+
+	tocOffset := cw.compOff
+	tocBuf := make([]byte, tocEntryLen*len(cw.toc))
+	for i, e := range cw.toc {
+		b := tocBuf[i*tocEntryLen:]
+		binary.LittleEndian.PutUint64(b[0:8], uint64(e.LogicalOffset))
+		binary.LittleEndian.PutUint64(b[8:16], uint64(e.CompressedOffset))
+		binary.LittleEndian.PutUint64(b[16:24], uint64(e.CompressedLen))
+		binary.LittleEndian.PutUint64(b[24:32], uint64(e.UncompressedLen))
+	}
+	if _, err := cw.w.Write(tocBuf); err != nil {
+		return err
+	}
+
+	var trailer [trailerLen]byte
+	binary.LittleEndian.PutUint32(trailer[0:4], skippableTOCMagic)
+	binary.LittleEndian.PutUint32(trailer[4:8], 16) // skippable frame User_Data size
+	binary.LittleEndian.PutUint64(trailer[8:16], uint64(tocOffset))
+	binary.LittleEndian.PutUint64(trailer[16:24], uint64(len(tocBuf)))
+	_, err := cw.w.Write(trailer[:])
+	return err
+}
+
+// chunkScratchPool hands out scratch []byte buffers for ChunkedZstdWriter's
+// chunk accumulation, sized to DefaultChunkedZstdChunkSize so the common
+// case never reallocates.
+var chunkScratchPool = pool.NewFixedPoolV2(func() []byte {
+	return make([]byte, 0, DefaultChunkedZstdChunkSize)
+}, 32)
+
+var chunkedZstdWriterPool sync.Pool
+
+// GetChunkedZstdWriter returns a pooled ChunkedZstdWriter writing to w with
+// the given chunkSize (<= 0 uses DefaultChunkedZstdChunkSize).
+func GetChunkedZstdWriter(w io.Writer, chunkSize int) *ChunkedZstdWriter {
+	v := chunkedZstdWriterPool.Get()
+	if v == nil {
+		return NewChunkedZstdWriter(w, chunkSize)
+	}
+	cw := v.(*ChunkedZstdWriter)
+	cw.reset(w, chunkSize)
+	return cw
+}
+
+// PutChunkedZstdWriter returns cw to the pool. It deliberately does not
+// return cw.buf to chunkScratchPool here: cw keeps its scratch buffer while
+// idle in chunkedZstdWriterPool, and only reset() (called when the writer
+// is pulled back out for reuse) hands it back, so the same backing array is
+// never simultaneously reachable through both pools. It does not call
+// Close; callers must do that themselves before returning cw.
+func PutChunkedZstdWriter(cw *ChunkedZstdWriter) {
+	chunkedZstdWriterPool.Put(cw)
+}
+
+// chunkLRU is a small fixed-capacity, least-recently-used cache of decoded
+// chunk payloads, keyed by TOC index. It exists purely to avoid
+// re-decompressing a frame that ReadAt just served, e.g. for a caller doing
+// several small, nearby reads.
+type chunkLRU struct {
+	cap     int
+	order   []int // most-recently-used at the end
+	entries map[int][]byte
+}
+
+func newChunkLRU(capacity int) *chunkLRU {
+	if capacity <= 0 {
+		capacity = 8
+	}
+	return &chunkLRU{cap: capacity, entries: make(map[int][]byte, capacity)}
+}
+
+func (c *chunkLRU) get(idx int) ([]byte, bool) {
+	v, ok := c.entries[idx]
+	if !ok {
+		return nil, false
+	}
+	c.touch(idx)
+	return v, true
+}
+
+func (c *chunkLRU) put(idx int, data []byte) {
+	if _, ok := c.entries[idx]; ok {
+		c.entries[idx] = data
+		c.touch(idx)
+		return
+	}
+	if len(c.entries) >= c.cap {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		delete(c.entries, oldest)
+	}
+	c.entries[idx] = data
+	c.order = append(c.order, idx)
+}
+
+func (c *chunkLRU) touch(idx int) {
+	for i, v := range c.order {
+		if v == idx {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, idx)
+}
+
+func (c *chunkLRU) reset() {
+	c.order = c.order[:0]
+	for k := range c.entries {
+		delete(c.entries, k)
+	}
+}
+
+// ErrNotChunkedZstd is returned by NewChunkedZstdReader when the trailer at
+// the end of the stream isn't the skippable TOC frame ChunkedZstdWriter
+// writes.
+var ErrNotChunkedZstd = errors.New("compress: not a chunked zstd stream")
+
+// ChunkedZstdReader provides random access into a stream written by
+// ChunkedZstdWriter: ReadAt decompresses only the frames that cover the
+// requested range, caching recently-decoded frames in a small LRU.
+type ChunkedZstdReader struct {
+	r    io.ReaderAt
+	size int64
+	toc  []chunkTOCEntry
+
+	mu    sync.Mutex
+	cache *chunkLRU
+}
+
+// NewChunkedZstdReader opens a ChunkedZstdReader over r, which must contain
+// size bytes written by a ChunkedZstdWriter (including its trailer).
+func NewChunkedZstdReader(r io.ReaderAt, size int64) (*ChunkedZstdReader, error) {
+	cr := &ChunkedZstdReader{cache: newChunkLRU(8)}
+	if err := cr.reset(r, size); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+func (cr *ChunkedZstdReader) reset(r io.ReaderAt, size int64) error {
+	if size < trailerLen {
+		return ErrNotChunkedZstd
+	}
+
+	var trailer [trailerLen]byte
+	if _, err := r.ReadAt(trailer[:], size-trailerLen); err != nil {
+		return fmt.Errorf("compress: reading chunked zstd trailer: %w", err)
+	}
+	if binary.LittleEndian.Uint32(trailer[0:4]) != skippableTOCMagic {
+		return ErrNotChunkedZstd
+	}
+	tocOffset := int64(binary.LittleEndian.Uint64(trailer[8:16]))
+	tocLen := int64(binary.LittleEndian.Uint64(trailer[16:24]))
+	if tocOffset < 0 || tocLen < 0 || tocOffset+tocLen > size-trailerLen {
+		return ErrNotChunkedZstd
+	}
+
+	tocBuf := make([]byte, tocLen)
+	if _, err := r.ReadAt(tocBuf, tocOffset); err != nil {
+		return fmt.Errorf("compress: reading chunked zstd TOC: %w", err)
+	}
+	if tocLen%tocEntryLen != 0 {
+		return ErrNotChunkedZstd
+	}
+
+	n := int(tocLen / tocEntryLen)
+	toc := make([]chunkTOCEntry, n)
+	for i := 0; i < n; i++ {
+		b := tocBuf[i*tocEntryLen:]
+		toc[i] = chunkTOCEntry{
+			LogicalOffset:    int64(binary.LittleEndian.Uint64(b[0:8])),
+			CompressedOffset: int64(binary.LittleEndian.Uint64(b[8:16])),
+			CompressedLen:    int64(binary.LittleEndian.Uint64(b[16:24])),
+			UncompressedLen:  int64(binary.LittleEndian.Uint64(b[24:32])),
+		}
+	}
+
+	cr.r = r
+	cr.size = size
+	cr.toc = toc
+	cr.cache.reset()
+	return nil
+}
+
+// chunkFor returns the index of the TOC entry covering logical offset off,
+// or -1 if off is past the end of the stream.
+func (cr *ChunkedZstdReader) chunkFor(off int64) int {
+	i := sort.Search(len(cr.toc), func(i int) bool {
+		return cr.toc[i].LogicalOffset+cr.toc[i].UncompressedLen > off
+	})
+	if i == len(cr.toc) {
+		return -1
+	}
+	return i
+}
+
+// decodeChunk returns the decompressed payload of TOC entry idx, decoding
+// it and populating the cache on a miss.
+func (cr *ChunkedZstdReader) decodeChunk(idx int) ([]byte, error) {
+	if data, ok := cr.cache.get(idx); ok {
+		return data, nil
+	}
+
+	e := cr.toc[idx]
+	compressed := make([]byte, e.CompressedLen)
+	if _, err := cr.r.ReadAt(compressed, e.CompressedOffset); err != nil {
+		return nil, err
+	}
+
+	zr := GetZstdReader(bytes.NewReader(compressed))
+	defer PutZstdReader(zr)
+
+	data := make([]byte, e.UncompressedLen)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return nil, err
+	}
+
+	cr.cache.put(idx, data)
+	return data, nil
+}
+
+// ReadAt implements io.ReaderAt, decompressing only the chunks that overlap
+// [off, off+len(p)).
+func (cr *ChunkedZstdReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("compress: negative ReadAt offset")
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	var read int
+	for len(p) > 0 {
+		idx := cr.chunkFor(off)
+		if idx < 0 {
+			if read == 0 {
+				return 0, io.EOF
+			}
+			return read, io.EOF
+		}
+
+		data, err := cr.decodeChunk(idx)
+		if err != nil {
+			return read, err
+		}
+
+		e := cr.toc[idx]
+		chunkOff := off - e.LogicalOffset
+		n := copy(p, data[chunkOff:])
+		p = p[n:]
+		off += int64(n)
+		read += n
+	}
+	return read, nil
+}
+
+var chunkedZstdReaderPool sync.Pool
+
+// GetChunkedZstdReader returns a pooled ChunkedZstdReader over r.
+func GetChunkedZstdReader(r io.ReaderAt, size int64) (*ChunkedZstdReader, error) {
+	v := chunkedZstdReaderPool.Get()
+	if v == nil {
+		return NewChunkedZstdReader(r, size)
+	}
+	cr := v.(*ChunkedZstdReader)
+	if err := cr.reset(r, size); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// PutChunkedZstdReader returns cr to the pool.
+func PutChunkedZstdReader(cr *ChunkedZstdReader) {
+	chunkedZstdReaderPool.Put(cr)
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildChunkedZstd(t *testing.T, chunkSize int, data []byte) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	cw := NewChunkedZstdWriter(&buf, chunkSize)
+	_, err := cw.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, cw.Close())
+	return &buf
+}
+
+func chunkedZstdTestData(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "line-%06d: the quick brown fox jumps over the lazy dog\n", i)
+	}
+	return buf.Bytes()
+}
+
+func TestChunkedZstdRoundTrip(t *testing.T) {
+	data := chunkedZstdTestData(5000)
+	buf := buildChunkedZstd(t, 4096, data)
+
+	cr, err := NewChunkedZstdReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.True(t, len(cr.toc) > 1, "input should have been split into multiple chunks")
+
+	got := make([]byte, len(data))
+	n, err := cr.ReadAt(got, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Equal(t, data, got)
+}
+
+func TestChunkedZstdReadAtRange(t *testing.T) {
+	data := chunkedZstdTestData(5000)
+	buf := buildChunkedZstd(t, 4096, data)
+
+	cr, err := NewChunkedZstdReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	start := int64(len(data) / 2)
+	want := data[start : start+100]
+	got := make([]byte, 100)
+	_, err = cr.ReadAt(got, start)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestChunkedZstdReadAtPastEnd(t *testing.T) {
+	data := chunkedZstdTestData(10)
+	buf := buildChunkedZstd(t, 4096, data)
+
+	cr, err := NewChunkedZstdReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	got := make([]byte, 10)
+	_, err = cr.ReadAt(got, int64(len(data))+1000)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestNewChunkedZstdReaderRejectsNonChunkedStream(t *testing.T) {
+	data := []byte("not a chunked zstd stream at all, but long enough to hold a trailer-sized tail")
+	_, err := NewChunkedZstdReader(bytes.NewReader(data), int64(len(data)))
+	assert.ErrorIs(t, err, ErrNotChunkedZstd)
+}
+
+func TestChunkedZstdWriterReaderPool(t *testing.T) {
+	data := chunkedZstdTestData(2000)
+
+	var buf bytes.Buffer
+	cw := GetChunkedZstdWriter(&buf, 4096)
+	_, err := cw.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, cw.Close())
+	PutChunkedZstdWriter(cw)
+
+	cr, err := GetChunkedZstdReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	got := make([]byte, len(data))
+	_, err = cr.ReadAt(got, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	PutChunkedZstdReader(cr)
+}
+
+// TestChunkedZstdWriterPoolConcurrent exercises GetChunkedZstdWriter/
+// PutChunkedZstdWriter from many goroutines at once, each round-tripping its
+// own data through its own writer. It guards against PutChunkedZstdWriter
+// handing a writer's scratch buffer to chunkScratchPool while the writer
+// itself, still holding that same buffer, is concurrently pulled back out
+// of chunkedZstdWriterPool by another goroutine: if that ever regresses,
+// two goroutines end up writing into the same backing array and this test
+// either races (under -race) or produces corrupted output.
+func TestChunkedZstdWriterPoolConcurrent(t *testing.T) {
+	const goroutines = 64
+	const iterations = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				data := chunkedZstdTestData(20 + j%5)
+
+				var buf bytes.Buffer
+				cw := GetChunkedZstdWriter(&buf, 512)
+				_, err := cw.Write(data)
+				assert.NoError(t, err)
+				assert.NoError(t, cw.Close())
+				PutChunkedZstdWriter(cw)
+
+				cr, err := GetChunkedZstdReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+				assert.NoError(t, err)
+				got := make([]byte, len(data))
+				_, err = cr.ReadAt(got, 0)
+				assert.NoError(t, err)
+				assert.Equal(t, data, got)
+				PutChunkedZstdReader(cr)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
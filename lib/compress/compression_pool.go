@@ -0,0 +1,247 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compress provides pooled compressors/decompressors for the
+// codecs used across openGemini's storage and replication paths, so
+// hot paths avoid re-allocating gzip/zstd/snappy/zlib state on every call.
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// defaultZlibMaxBufSize is the default cap on the amount of data a pooled
+// zlib writer/reader may have processed since its last reset before it is
+// dropped instead of being returned to the pool. This keeps a handful of
+// outlier large streams from pinning their compressor/decompressor state
+// (hash tables, sliding windows) in memory indefinitely.
+const defaultZlibMaxBufSize = 1 << 20 // 1MB
+
+var gzipWriterPool sync.Pool
+
+// GetGzipWriter returns a pooled *gzip.Writer reset to write to w.
+func GetGzipWriter(w io.Writer) *gzip.Writer {
+	v := gzipWriterPool.Get()
+	if v == nil {
+		zw, _ := gzip.NewWriterLevel(w, gzip.DefaultCompression)
+		return zw
+	}
+	zw := v.(*gzip.Writer)
+	zw.Reset(w)
+	return zw
+}
+
+// PutGzipWriter closes w and returns it to the pool.
+func PutGzipWriter(w *gzip.Writer) {
+	_ = w.Close()
+	gzipWriterPool.Put(w)
+}
+
+var zstdWriterPool sync.Pool
+var zstdReaderPool sync.Pool
+
+// GetZstdWriter returns a pooled *zstd.Encoder reset to write to w.
+func GetZstdWriter(w io.Writer) *zstd.Encoder {
+	v := zstdWriterPool.Get()
+	if v == nil {
+		zw, _ := zstd.NewWriter(w)
+		return zw
+	}
+	zw := v.(*zstd.Encoder)
+	zw.Reset(w)
+	return zw
+}
+
+// PutZstdWriter closes w and returns it to the pool.
+func PutZstdWriter(w *zstd.Encoder) {
+	_ = w.Close()
+	zstdWriterPool.Put(w)
+}
+
+// GetZstdReader returns a pooled *zstd.Decoder reset to read from r.
+func GetZstdReader(r io.Reader) *zstd.Decoder {
+	v := zstdReaderPool.Get()
+	if v == nil {
+		zr, _ := zstd.NewReader(r)
+		return zr
+	}
+	zr := v.(*zstd.Decoder)
+	_ = zr.Reset(r)
+	return zr
+}
+
+// PutZstdReader returns r to the pool.
+func PutZstdReader(r *zstd.Decoder) {
+	zstdReaderPool.Put(r)
+}
+
+var snappyWriterPool sync.Pool
+var snappyReaderPool sync.Pool
+
+// GetSnappyWriter returns a pooled *snappy.Writer reset to write to w.
+func GetSnappyWriter(w io.Writer) *snappy.Writer {
+	v := snappyWriterPool.Get()
+	if v == nil {
+		return snappy.NewBufferedWriter(w)
+	}
+	sw := v.(*snappy.Writer)
+	sw.Reset(w)
+	return sw
+}
+
+// PutSnappyWriter closes w and returns it to the pool.
+func PutSnappyWriter(w *snappy.Writer) {
+	_ = w.Close()
+	snappyWriterPool.Put(w)
+}
+
+// GetSnappyReader returns a pooled *snappy.Reader reset to read from r.
+func GetSnappyReader(r io.Reader) *snappy.Reader {
+	v := snappyReaderPool.Get()
+	if v == nil {
+		return snappy.NewReader(r)
+	}
+	sr := v.(*snappy.Reader)
+	sr.Reset(r)
+	return sr
+}
+
+// PutSnappyReader returns r to the pool.
+func PutSnappyReader(r *snappy.Reader) {
+	snappyReaderPool.Put(r)
+}
+
+// zlibWriter wraps *zlib.Writer with a running count of bytes written since
+// the last reset, so Put can decide whether the writer's internal state has
+// grown past the pool's cap.
+type zlibWriter struct {
+	*zlib.Writer
+	n int64
+}
+
+func (w *zlibWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// zlibReader wraps the io.ReadCloser returned by zlib.NewReader together
+// with the zlib.Resetter view of the same value, plus a byte counter used
+// for the same cap check as zlibWriter. zlib.NewReader hands back a freshly
+// allocated, unexported *zlib.reader on every call, and the only way to
+// reuse one is through its Reset(io.Reader, []byte) method, which is reached
+// via the zlib.Resetter interface rather than the concrete type. go-git's
+// pooled zlib reader uses the same trick.
+type zlibReader struct {
+	io.ReadCloser
+	resetter zlib.Resetter
+	n        int64
+}
+
+func (r *zlibReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// ZlibPool is a level- and dictionary-scoped pair of writer/reader pools for
+// compress/zlib. A process may need more than one level/dictionary
+// combination (e.g. WAL replication frames vs. packfile-style object
+// streams), so callers create one ZlibPool per combination instead of
+// sharing a single global pool the way gzip/zstd/snappy do above.
+type ZlibPool struct {
+	level     int
+	dict      []byte
+	maxBufLen int64
+
+	writers sync.Pool
+	readers sync.Pool
+}
+
+// NewZlibPool creates a zlib writer/reader pool at the given compression
+// level with an optional preset dictionary (nil for none). maxBufLen caps
+// the number of bytes a pooled writer/reader may process between resets
+// before it is discarded instead of pooled; maxBufLen <= 0 uses
+// defaultZlibMaxBufSize.
+func NewZlibPool(level int, dict []byte, maxBufLen int64) *ZlibPool {
+	if maxBufLen <= 0 {
+		maxBufLen = defaultZlibMaxBufSize
+	}
+	return &ZlibPool{level: level, dict: dict, maxBufLen: maxBufLen}
+}
+
+// GetZlibWriter returns a pooled zlib writer reset to write to w.
+func (p *ZlibPool) GetZlibWriter(w io.Writer) *zlibWriter {
+	v := p.writers.Get()
+	if v == nil {
+		zw, err := zlib.NewWriterLevelDict(w, p.level, p.dict)
+		if err != nil {
+			zw, _ = zlib.NewWriterLevelDict(w, zlib.DefaultCompression, p.dict)
+		}
+		return &zlibWriter{Writer: zw}
+	}
+	zw := v.(*zlibWriter)
+	zw.Writer.Reset(w)
+	zw.n = 0
+	return zw
+}
+
+// PutZlibWriter flushes and closes w and returns it to the pool, unless it
+// has written more than the pool's maxBufLen since its last reset, in which
+// case it is dropped so the pool doesn't pin large compressor state.
+func (p *ZlibPool) PutZlibWriter(w *zlibWriter) {
+	_ = w.Close()
+	if w.n > p.maxBufLen {
+		return
+	}
+	p.writers.Put(w)
+}
+
+// GetZlibReader returns a pooled zlib reader reset to read from r.
+func (p *ZlibPool) GetZlibReader(r io.Reader) (*zlibReader, error) {
+	v := p.readers.Get()
+	if v == nil {
+		rc, err := zlib.NewReaderDict(r, p.dict)
+		if err != nil {
+			return nil, err
+		}
+		resetter, ok := rc.(zlib.Resetter)
+		if !ok {
+			return nil, zlib.ErrHeader
+		}
+		return &zlibReader{ReadCloser: rc, resetter: resetter}, nil
+	}
+	zr := v.(*zlibReader)
+	if err := zr.resetter.Reset(r, p.dict); err != nil {
+		return nil, err
+	}
+	zr.n = 0
+	return zr, nil
+}
+
+// PutZlibReader returns r to the pool, unless it has read more than the
+// pool's maxBufLen since its last reset, in which case it is dropped so the
+// pool doesn't pin large decompressor state.
+func (p *ZlibPool) PutZlibReader(r *zlibReader) {
+	if r.n > p.maxBufLen {
+		return
+	}
+	p.readers.Put(r)
+}
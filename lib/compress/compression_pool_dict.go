@@ -0,0 +1,170 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/openGemini/openGemini/lib/compress/zstddict"
+)
+
+// ErrDictNotFound is returned by GetZstdWriterWithDict / GetZstdReaderWithDict
+// when dictID has not been registered with zstddict.
+var ErrDictNotFound = errors.New("compress: zstd dictionary not registered")
+
+// DefaultZstdDictLevel is the encoder level used by GetZstdWriterWithDict.
+// It is a package variable rather than a parameter because encoders built
+// against a dictionary are pooled per (level, dictID): changing it only
+// affects writers created after the change, while writers already pooled
+// at the old level simply age out of their own, now-unused pool.
+var DefaultZstdDictLevel = zstd.SpeedDefault
+
+// zstdDictWriterKey identifies a dictionary- and level-scoped writer pool,
+// so an encoder trained for one dictionary or level is never handed back
+// for another.
+type zstdDictWriterKey struct {
+	level  zstd.EncoderLevel
+	dictID uint32
+}
+
+// zstdDictWriterEntry pairs a writer pool with the dictionary content its
+// encoders are built from. The dictionary is acquired once, the first time
+// the (level, dictID) pair is seen, and held for as long as the pool exists
+// rather than re-acquired on every Get.
+type zstdDictWriterEntry struct {
+	pool    sync.Pool
+	content []byte
+}
+
+// zstdDictReaderEntry is the decoder-side equivalent of zstdDictWriterEntry,
+// keyed only by dictID since decoding does not depend on encoder level.
+type zstdDictReaderEntry struct {
+	pool    sync.Pool
+	content []byte
+}
+
+var zstdDictWriterPools sync.Map // zstdDictWriterKey -> *zstdDictWriterEntry
+var zstdDictReaderPools sync.Map // uint32 (dictID) -> *zstdDictReaderEntry
+
+func zstdDictWriterEntryFor(key zstdDictWriterKey) (*zstdDictWriterEntry, error) {
+	if v, ok := zstdDictWriterPools.Load(key); ok {
+		return v.(*zstdDictWriterEntry), nil
+	}
+
+	content, ok := zstddict.Acquire(key.dictID)
+	if !ok {
+		return nil, ErrDictNotFound
+	}
+
+	e := &zstdDictWriterEntry{content: content}
+	actual, loaded := zstdDictWriterPools.LoadOrStore(key, e)
+	if loaded {
+		// Lost the race to another goroutine creating the same pool; drop
+		// our extra reference and use theirs.
+		zstddict.Release(key.dictID)
+		return actual.(*zstdDictWriterEntry), nil
+	}
+	return e, nil
+}
+
+func zstdDictReaderEntryFor(dictID uint32) (*zstdDictReaderEntry, error) {
+	if v, ok := zstdDictReaderPools.Load(dictID); ok {
+		return v.(*zstdDictReaderEntry), nil
+	}
+
+	content, ok := zstddict.Acquire(dictID)
+	if !ok {
+		return nil, ErrDictNotFound
+	}
+
+	e := &zstdDictReaderEntry{content: content}
+	actual, loaded := zstdDictReaderPools.LoadOrStore(dictID, e)
+	if loaded {
+		zstddict.Release(dictID)
+		return actual.(*zstdDictReaderEntry), nil
+	}
+	return e, nil
+}
+
+// GetZstdWriterWithDict returns a pooled *zstd.Encoder reset to write to w,
+// preloaded with the dictionary registered under dictID, and the level it
+// was built at. Callers must pass that level back to PutZstdWriterWithDict
+// unchanged: DefaultZstdDictLevel is read once here, not re-read on Put, so
+// a change to DefaultZstdDictLevel between a Get and its matching Put can
+// never file the encoder into the wrong (level, dictID) pool.
+func GetZstdWriterWithDict(w io.Writer, dictID uint32) (*zstd.Encoder, int, error) {
+	level := DefaultZstdDictLevel
+	key := zstdDictWriterKey{level: level, dictID: dictID}
+	e, err := zstdDictWriterEntryFor(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	v := e.pool.Get()
+	if v == nil {
+		zw, err := zstd.NewWriter(w,
+			zstd.WithEncoderLevel(level),
+			zstd.WithEncoderDictRaw(dictID, e.content))
+		return zw, int(level), err
+	}
+	zw := v.(*zstd.Encoder)
+	zw.Reset(w)
+	return zw, int(level), nil
+}
+
+// PutZstdWriterWithDict closes w and returns it to the (level, dictID) pool
+// it was built against, using the level GetZstdWriterWithDict returned
+// alongside it rather than re-reading DefaultZstdDictLevel.
+func PutZstdWriterWithDict(w *zstd.Encoder, dictID uint32, level int) {
+	_ = w.Close()
+	key := zstdDictWriterKey{level: zstd.EncoderLevel(level), dictID: dictID}
+	e, ok := zstdDictWriterPools.Load(key)
+	if !ok {
+		return
+	}
+	e.(*zstdDictWriterEntry).pool.Put(w)
+}
+
+// GetZstdReaderWithDict returns a pooled *zstd.Decoder reset to read from r,
+// preloaded with the dictionary registered under dictID.
+func GetZstdReaderWithDict(r io.Reader, dictID uint32) (*zstd.Decoder, error) {
+	e, err := zstdDictReaderEntryFor(dictID)
+	if err != nil {
+		return nil, err
+	}
+
+	v := e.pool.Get()
+	if v == nil {
+		return zstd.NewReader(r, zstd.WithDecoderDictRaw(dictID, e.content))
+	}
+	zr := v.(*zstd.Decoder)
+	if err := zr.Reset(r); err != nil {
+		return nil, err
+	}
+	return zr, nil
+}
+
+// PutZstdReaderWithDict returns r to its dictID pool.
+func PutZstdReaderWithDict(r *zstd.Decoder, dictID uint32) {
+	e, ok := zstdDictReaderPools.Load(dictID)
+	if !ok {
+		return
+	}
+	e.(*zstdDictReaderEntry).pool.Put(r)
+}
@@ -17,12 +17,16 @@ package compress
 import (
 	"bytes"
 	"compress/gzip"
+	"compress/zlib"
+	"fmt"
 	"io"
 	"testing"
 
 	"github.com/golang/snappy"
 	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/openGemini/openGemini/lib/compress/zstddict"
 )
 
 func TestGzipWriterPool(t *testing.T) {
@@ -111,3 +115,133 @@ func TestZstdReaderPool(t *testing.T) {
 	// Put Zstd reader back to pool
 	PutZstdReader(reader)
 }
+
+func TestZlibWriterReaderPool(t *testing.T) {
+	pool := NewZlibPool(zlib.DefaultCompression, nil, 0)
+
+	var buf bytes.Buffer
+	writer := pool.GetZlibWriter(&buf)
+	_, err := writer.Write([]byte("test data"))
+	assert.NoError(t, err)
+	pool.PutZlibWriter(writer)
+
+	reader, err := pool.GetZlibReader(&buf)
+	assert.NoError(t, err)
+
+	result := new(bytes.Buffer)
+	_, err = io.Copy(result, reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "test data", result.String())
+
+	pool.PutZlibReader(reader)
+}
+
+func TestZlibPoolWithDict(t *testing.T) {
+	dict := []byte("test")
+	pool := NewZlibPool(zlib.BestSpeed, dict, 0)
+
+	var buf bytes.Buffer
+	writer := pool.GetZlibWriter(&buf)
+	_, err := writer.Write([]byte("test data"))
+	assert.NoError(t, err)
+	pool.PutZlibWriter(writer)
+
+	reader, err := pool.GetZlibReader(&buf)
+	assert.NoError(t, err)
+
+	result := new(bytes.Buffer)
+	_, err = io.Copy(result, reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "test data", result.String())
+
+	pool.PutZlibReader(reader)
+}
+
+func TestZstdWriterReaderPoolWithDict(t *testing.T) {
+	corpus := make([][]byte, 500)
+	for i := range corpus {
+		corpus[i] = []byte(fmt.Sprintf(
+			"measurement=cpu,host=server%03d,region=us-west-1,az=a usage_idle=%d.9,usage_user=0.1,usage_system=0.05",
+			i%50, i%100))
+	}
+	blob, err := zstddict.Train(corpus, zstddict.TrainOptions{MaxDictSize: 4096, ID: 123})
+	assert.NoError(t, err)
+	zstddict.Register(123, blob)
+
+	var buf bytes.Buffer
+	writer, level, err := GetZstdWriterWithDict(&buf, 123)
+	assert.NoError(t, err)
+	_, err = writer.Write([]byte("measurement=cpu,host=server02 usage_idle=12.3"))
+	assert.NoError(t, err)
+	PutZstdWriterWithDict(writer, 123, level)
+
+	reader, err := GetZstdReaderWithDict(&buf, 123)
+	assert.NoError(t, err)
+
+	result := new(bytes.Buffer)
+	_, err = io.Copy(result, reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "measurement=cpu,host=server02 usage_idle=12.3", result.String())
+
+	PutZstdReaderWithDict(reader, 123)
+}
+
+func TestZstdWriterWithDictUnknownID(t *testing.T) {
+	var buf bytes.Buffer
+	_, _, err := GetZstdWriterWithDict(&buf, 999999)
+	assert.Equal(t, ErrDictNotFound, err)
+}
+
+// TestZstdWriterWithDictSurvivesLevelChange covers a Get/Put pair straddling
+// a change to DefaultZstdDictLevel: Put must file the encoder into the pool
+// for the level it was actually built at, not whatever DefaultZstdDictLevel
+// happens to be by the time Put runs.
+func TestZstdWriterWithDictSurvivesLevelChange(t *testing.T) {
+	corpus := make([][]byte, 500)
+	for i := range corpus {
+		corpus[i] = []byte(fmt.Sprintf(
+			"measurement=mem,host=server%03d,region=us-east-1,az=b used_percent=%d.9,free=0.1",
+			i%50, i%100))
+	}
+	blob, err := zstddict.Train(corpus, zstddict.TrainOptions{MaxDictSize: 4096, ID: 456})
+	assert.NoError(t, err)
+	zstddict.Register(456, blob)
+
+	orig := DefaultZstdDictLevel
+	defer func() { DefaultZstdDictLevel = orig }()
+	DefaultZstdDictLevel = zstd.SpeedFastest
+
+	var buf bytes.Buffer
+	writer, level, err := GetZstdWriterWithDict(&buf, 456)
+	assert.NoError(t, err)
+	assert.Equal(t, int(zstd.SpeedFastest), level)
+
+	// Simulate another caller changing the package-level default between
+	// this Get and its matching Put.
+	DefaultZstdDictLevel = zstd.SpeedBestCompression
+
+	_, err = writer.Write([]byte("measurement=mem,host=server02 used_percent=55.1"))
+	assert.NoError(t, err)
+	PutZstdWriterWithDict(writer, 456, level)
+
+	key := zstdDictWriterKey{level: zstd.SpeedFastest, dictID: 456}
+	e, ok := zstdDictWriterPools.Load(key)
+	assert.True(t, ok)
+	pooled := e.(*zstdDictWriterEntry).pool.Get()
+	assert.Same(t, writer, pooled, "Put must return the encoder to the level it was built at, not the current DefaultZstdDictLevel")
+}
+
+func TestZlibPoolDiscardsOversizedBuffers(t *testing.T) {
+	pool := NewZlibPool(zlib.DefaultCompression, nil, 4)
+
+	var buf bytes.Buffer
+	writer := pool.GetZlibWriter(&buf)
+	_, err := writer.Write([]byte("test data"))
+	assert.NoError(t, err)
+	pool.PutZlibWriter(writer)
+
+	// The writer exceeded the 4-byte cap, so a fresh one must be allocated
+	// rather than reused from the pool.
+	next := pool.GetZlibWriter(&buf)
+	assert.NotSame(t, writer, next)
+}
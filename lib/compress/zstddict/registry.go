@@ -0,0 +1,118 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zstddict
+
+import "sync"
+
+// entry holds a registered dictionary and the number of Acquire calls on it
+// not yet matched by a Release.
+type entry struct {
+	content []byte
+	refs    int
+}
+
+// Registry maps a dictionary ID to its trained content, reference-counted so
+// a dictionary in active use by pooled encoders/decoders is never evicted
+// out from under them.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[uint32]*entry
+}
+
+// NewRegistry creates an empty dictionary registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[uint32]*entry)}
+}
+
+// Default is the process-wide registry used by the package-level helpers
+// and by compress.GetZstdWriterWithDict / GetZstdReaderWithDict.
+var Default = NewRegistry()
+
+// Register adds or replaces the dictionary content for id. It does not
+// affect the reference count of an existing entry.
+func (r *Registry) Register(id uint32, content []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[id]; ok {
+		e.content = content
+		return
+	}
+	r.entries[id] = &entry{content: content}
+}
+
+// Acquire looks up the dictionary content for id and increments its
+// reference count. Every successful Acquire must be matched by a Release
+// once the caller is done with the content (typically when the pooled
+// encoder/decoder built against it is discarded).
+func (r *Registry) Acquire(id uint32) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[id]
+	if !ok {
+		return nil, false
+	}
+	e.refs++
+	return e.content, true
+}
+
+// Release decrements the reference count for id. It is a no-op if id is not
+// registered.
+func (r *Registry) Release(id uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[id]
+	if !ok || e.refs == 0 {
+		return
+	}
+	e.refs--
+}
+
+// RefCount returns the current reference count for id, or 0 if id is not
+// registered.
+func (r *Registry) RefCount(id uint32) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.entries[id]
+	if !ok {
+		return 0
+	}
+	return e.refs
+}
+
+// Evict removes id from the registry and reports whether it did so. An
+// entry with a non-zero reference count is left in place.
+func (r *Registry) Evict(id uint32) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[id]
+	if !ok || e.refs > 0 {
+		return false
+	}
+	delete(r.entries, id)
+	return true
+}
+
+// Register, Acquire, Release, RefCount and Evict are convenience wrappers
+// that operate on the Default registry.
+func Register(id uint32, content []byte) { Default.Register(id, content) }
+func Acquire(id uint32) ([]byte, bool)   { return Default.Acquire(id) }
+func Release(id uint32)                  { Default.Release(id) }
+func RefCount(id uint32) int             { return Default.RefCount(id) }
+func Evict(id uint32) bool               { return Default.Evict(id) }
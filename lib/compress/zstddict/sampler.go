@@ -0,0 +1,92 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zstddict
+
+import "sync"
+
+// Sampler accumulates a training corpus out of a stream of small payloads
+// (column blocks, series keys, tag values, ...) so it can later be handed to
+// Train. Payloads are copied on Add, so the caller's buffer can be reused or
+// released immediately after the call returns.
+type Sampler struct {
+	mu sync.Mutex
+
+	samples   [][]byte
+	totalSize int
+
+	maxSamples int
+	maxTotal   int
+}
+
+// NewSampler creates a Sampler that stops accepting payloads once it holds
+// maxSamples of them, and refuses any payload that would take their
+// combined size past maxTotalSize.
+func NewSampler(maxSamples, maxTotalSize int) *Sampler {
+	return &Sampler{
+		maxSamples: maxSamples,
+		maxTotal:   maxTotalSize,
+	}
+}
+
+// Add copies payload into the training corpus and reports whether it was
+// accepted. It returns false for an empty payload, once maxSamples is
+// reached, or when payload would take the combined size past maxTotalSize;
+// callers should treat a refusal as a signal to stop sampling and call Train.
+func (s *Sampler) Add(payload []byte) bool {
+	if len(payload) == 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.samples) >= s.maxSamples || s.totalSize+len(payload) > s.maxTotal {
+		return false
+	}
+
+	cp := make([]byte, len(payload))
+	copy(cp, payload)
+	s.samples = append(s.samples, cp)
+	s.totalSize += len(payload)
+	return true
+}
+
+// Len returns the number of samples collected so far.
+func (s *Sampler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.samples)
+}
+
+// Samples returns a snapshot of the collected training corpus. The returned
+// slice and its elements must not be mutated by the caller.
+func (s *Sampler) Samples() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([][]byte, len(s.samples))
+	copy(out, s.samples)
+	return out
+}
+
+// Reset discards the collected corpus so the sampler can be reused for the
+// next training round.
+func (s *Sampler) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = nil
+	s.totalSize = 0
+}
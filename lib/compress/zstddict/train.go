@@ -0,0 +1,85 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zstddict trains zstd dictionaries out of a sample of small shard
+// payloads (column blocks, series keys, tag values, ...) and keeps the
+// resulting dictionary blobs available, reference-counted and keyed by the
+// 32-bit dictionary ID stored alongside the data that was compressed with
+// them. Raw zstd underperforms on openGemini's many small tag/field
+// payloads because there isn't enough data in any single payload to build a
+// useful compression history; a shared, trained dictionary fixes that.
+package zstddict
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/klauspost/compress/dict"
+)
+
+// ErrNoSamples is returned by Train when the supplied corpus is empty.
+var ErrNoSamples = errors.New("zstddict: no samples to train on")
+
+// ErrNotADictionary is returned by DictID when content does not start with
+// the zstd dictionary magic number.
+var ErrNotADictionary = errors.New("zstddict: content is not a zstd dictionary")
+
+// defaultHashBytes is the match length used by the dictionary trainer when
+// the caller doesn't care to tune it.
+const defaultHashBytes = 6
+
+// dictMagic is the 4-byte little-endian magic number every zstd dictionary
+// blob starts with, per the zstd dictionary format.
+const dictMagic = 0xEC30A437
+
+// dictIDOffset is the byte offset of the 32-bit dictionary ID within a zstd
+// dictionary blob, immediately following the magic number.
+const dictIDOffset = 4
+
+// TrainOptions configures Train.
+type TrainOptions struct {
+	// MaxDictSize is the maximum size, in bytes, of the produced dictionary.
+	MaxDictSize int
+
+	// ID is the dictionary ID to embed in the trained blob. If zero, the
+	// underlying trainer assigns a random ID.
+	ID uint32
+}
+
+// Train builds a zstd dictionary from samples, suitable for persisting
+// alongside a shard and for use with compress.GetZstdWriterWithDict /
+// GetZstdReaderWithDict. The returned blob already has its dictionary ID
+// embedded; retrieve it afterwards with DictID rather than threading it
+// through separately.
+func Train(samples [][]byte, o TrainOptions) ([]byte, error) {
+	if len(samples) == 0 {
+		return nil, ErrNoSamples
+	}
+
+	return dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: o.MaxDictSize,
+		HashBytes:   defaultHashBytes,
+		ZstdDictID:  o.ID,
+	})
+}
+
+// DictID extracts the dictionary ID embedded in a trained dictionary blob,
+// the same ID that should be stored in the block header of data compressed
+// with it.
+func DictID(content []byte) (uint32, error) {
+	if len(content) < dictIDOffset+4 || binary.LittleEndian.Uint32(content) != dictMagic {
+		return 0, ErrNotADictionary
+	}
+	return binary.LittleEndian.Uint32(content[dictIDOffset:]), nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zstddict
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sampleCorpus builds a training corpus that mimics a stream of tag/field
+// payloads: a shared shape with enough per-entry variance that the
+// dictionary trainer finds real recurring substrings to extract.
+func sampleCorpus(n int) [][]byte {
+	corpus := make([][]byte, n)
+	for i := range corpus {
+		corpus[i] = []byte(fmt.Sprintf(
+			"measurement=cpu,host=server%03d,region=us-west-1,az=a usage_idle=%d.9,usage_user=0.1,usage_system=0.05",
+			i%50, i%100))
+	}
+	return corpus
+}
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(3, 1<<20)
+
+	assert.True(t, s.Add([]byte("a")))
+	assert.True(t, s.Add([]byte("b")))
+	assert.True(t, s.Add([]byte("c")))
+	assert.False(t, s.Add([]byte("d")), "sampler should refuse once maxSamples is reached")
+	assert.Equal(t, 3, s.Len())
+
+	samples := s.Samples()
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")}, samples)
+
+	s.Reset()
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestSamplerRespectsTotalSizeCap(t *testing.T) {
+	s := NewSampler(100, 5)
+
+	assert.True(t, s.Add([]byte("abc")))
+	assert.False(t, s.Add([]byte("abc")), "sampler should refuse a payload that would exceed maxTotalSize")
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestTrainAndDictID(t *testing.T) {
+	blob, err := Train(sampleCorpus(64), TrainOptions{MaxDictSize: 4096, ID: 42})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, blob)
+
+	id, err := DictID(blob)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(42), id)
+}
+
+func TestTrainNoSamples(t *testing.T) {
+	_, err := Train(nil, TrainOptions{MaxDictSize: 4096})
+	assert.Equal(t, ErrNoSamples, err)
+}
+
+func TestDictIDRejectsNonDictionaryContent(t *testing.T) {
+	_, err := DictID([]byte("not a dictionary"))
+	assert.Equal(t, ErrNotADictionary, err)
+}
+
+func TestRegistryRefCounting(t *testing.T) {
+	r := NewRegistry()
+	r.Register(7, []byte("dict-content"))
+
+	content, ok := r.Acquire(7)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("dict-content"), content)
+	assert.Equal(t, 1, r.RefCount(7))
+
+	assert.False(t, r.Evict(7), "an in-use dictionary must not be evicted")
+
+	r.Release(7)
+	assert.Equal(t, 0, r.RefCount(7))
+	assert.True(t, r.Evict(7))
+
+	_, ok = r.Acquire(7)
+	assert.False(t, ok)
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync"
+	_ "unsafe" // for go:linkname
+
+	"github.com/openGemini/openGemini/lib/bufferpool"
+	"github.com/openGemini/openGemini/lib/cpu"
+)
+
+// runtime_procPin/runtime_procUnpin are the same runtime hooks the standard
+// library's sync.Pool uses to find the calling goroutine's current P without
+// allocating: procPin disables preemption and returns the P id, so a
+// Get/Put pair from the same goroutine lands on the same shard far more
+// often than a plain round robin would, at negligible cost.
+//
+// This is the one place in the package that reaches past the sync/cpu
+// primitives the rest of the pool code uses (FixedPoolV2, ObjectPool, ...),
+// because none of them need per-P shard affinity: their pools are either a
+// single shared structure or already keyed some other way. sync.Pool's own
+// Get/Put rely on exactly this pin for the same reason ObjectPoolV2 does
+// here, which is why it's reasonable to borrow rather than reinvent it.
+// Callers must call runtime_procUnpin as soon as they're done reading
+// p.shards — never while running caller-supplied code (stat hooks, new) —
+// since pinning disables preemption on the current P.
+//
+//go:linkname runtime_procPin sync.runtime_procPin
+func runtime_procPin() int
+
+//go:linkname runtime_procUnpin sync.runtime_procUnpin
+func runtime_procUnpin()
+
+// ObjectV2 is the constraint ObjectPoolV2 requires. Unlike Object, which
+// needs an awkward Instance() self-factory method so ObjectPool can mint
+// replacements, a value only needs to know its own memory footprint and how
+// to reset itself for its next use.
+type ObjectV2 interface {
+	MemSize() int
+	Reset()
+}
+
+// objectV2Shard is one of ObjectPoolV2's per-shard local caches.
+type objectV2Shard[T ObjectV2] struct {
+	local chan T
+}
+
+// ObjectPoolV2 is ObjectPool's generic, sharded successor: it avoids boxing
+// values into interface{}/Object, and spreads its local cache across
+// cpu.GetCpuNum() shards instead of a single chan, so concurrent
+// Get/Put from unrelated goroutines contend on one shared channel less
+// often.
+type ObjectPoolV2[T ObjectV2] struct {
+	shards []objectV2Shard[T]
+
+	overflow sync.Pool // catches Puts that find their shard's local cache full
+	new      func() T
+
+	maxLocalCacheSize int
+	hook              *HitRatioHook
+}
+
+// defaultShardLocalLen is the minimum per-shard local cache size: it is used
+// whenever the pool's overall size (bufferpool.MaxLocalCacheLen) spread
+// across cpu.GetCpuNum() shards comes out smaller than it.
+const defaultShardLocalLen = 4
+
+// NewObjectPoolV2 creates an ObjectPoolV2 whose Get calls new on a cache
+// miss, and whose Put discards values larger than maxLocalCacheSize rather
+// than pooling them.
+func NewObjectPoolV2[T ObjectV2](new func() T, maxLocalCacheSize int) *ObjectPoolV2[T] {
+	n := cpu.GetCpuNum()
+	if n < 1 {
+		n = 1
+	}
+
+	shardLen := bufferpool.MaxLocalCacheLen / n
+	if shardLen < defaultShardLocalLen {
+		shardLen = defaultShardLocalLen
+	}
+
+	shards := make([]objectV2Shard[T], n)
+	for i := range shards {
+		shards[i].local = make(chan T, shardLen)
+	}
+
+	return &ObjectPoolV2[T]{
+		shards:            shards,
+		new:               new,
+		maxLocalCacheSize: maxLocalCacheSize,
+	}
+}
+
+// SetHitRatioHook installs a hook whose IncrTotal/IncrHit are called on
+// every Get, aggregated across all shards.
+func (p *ObjectPoolV2[T]) SetHitRatioHook(hook *HitRatioHook) {
+	p.hook = hook
+}
+
+func (p *ObjectPoolV2[T]) stat(hit bool) {
+	if p.hook == nil {
+		return
+	}
+	p.hook.IncrTotal(1)
+	if hit {
+		p.hook.IncrHit(1)
+	}
+}
+
+// shard picks the local cache for the calling goroutine's current P, pinned
+// for the duration of the caller's critical section so the P can't change
+// underneath it.
+func (p *ObjectPoolV2[T]) shard() *objectV2Shard[T] {
+	pid := runtime_procPin()
+	return &p.shards[pid%len(p.shards)]
+}
+
+// Get returns an item from the calling goroutine's shard local cache,
+// falling back to the shared overflow pool and finally to new() on a full
+// miss. The P stays pinned only for the local-cache lookup; stat() and
+// new() — both of which can run arbitrary caller-supplied code — execute
+// after it's unpinned, matching how sync.Pool.Get itself unpins before
+// calling New.
+func (p *ObjectPoolV2[T]) Get() T {
+	shard := p.shard()
+	select {
+	case v := <-shard.local:
+		runtime_procUnpin()
+		p.stat(true)
+		return v
+	default:
+		runtime_procUnpin()
+	}
+
+	if v, ok := p.overflow.Get().(T); ok {
+		p.stat(true)
+		return v
+	}
+
+	p.stat(false)
+	return p.new()
+}
+
+// Put resets v and returns it to the calling goroutine's shard local cache,
+// spilling into the shared overflow pool if that shard is full. Values
+// whose MemSize exceeds maxLocalCacheSize are dropped instead. This is the
+// same threshold ObjectPool applies, but ObjectPool still hands such values
+// to its sync.Pool, whereas ObjectPoolV2 never pools them. As in Get, the P is unpinned as soon as the local
+// cache decision is made, before falling back to the overflow pool.
+func (p *ObjectPoolV2[T]) Put(v T) {
+	if v.MemSize() > p.maxLocalCacheSize {
+		return
+	}
+	v.Reset()
+
+	shard := p.shard()
+	select {
+	case shard.local <- v:
+		runtime_procUnpin()
+	default:
+		runtime_procUnpin()
+		p.overflow.Put(v)
+	}
+}
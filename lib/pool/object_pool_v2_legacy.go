@@ -0,0 +1,61 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+// legacyResettable is implemented by Object types that have grown a real
+// in-place reset. LegacyObject prefers it over Instance() wherever
+// possible, since minting a fresh instance through Instance() on every Put
+// allocates where an in-place reset wouldn't — the whole point of pooling.
+// Object implementations migrating onto ObjectPoolV2 should add a Reset()
+// method rather than leaving LegacyObject to fall back to Instance().
+type legacyResettable interface {
+	Reset()
+}
+
+// LegacyObject wraps an existing Object so it can be handed to
+// ObjectPoolV2 before its type has grown a real Reset method. This is
+// strictly a migration aid — give the Object type a proper Reset and
+// switch to it directly once practical.
+type LegacyObject struct {
+	Object
+}
+
+// MemSize reports the wrapped Object's memory footprint.
+func (l *LegacyObject) MemSize() int {
+	return l.Object.MemSize()
+}
+
+// Reset resets the wrapped Object in place via legacyResettable when it
+// implements one. Otherwise it falls back to replacing it with a fresh
+// instance from Instance(), mimicking in-place reset for callers that only
+// implemented Object — at the cost of an allocation on every Put, which is
+// the reason to add a real Reset() instead of relying on this fallback.
+func (l *LegacyObject) Reset() {
+	if r, ok := l.Object.(legacyResettable); ok {
+		r.Reset()
+		return
+	}
+	l.Object = l.Object.Instance()
+}
+
+// NewLegacyObjectPoolV2 builds an ObjectPoolV2 around an existing Object
+// implementation, for call sites migrating off ObjectPool incrementally.
+// proto is only used to mint further instances via its Instance() method;
+// it is never itself returned from Get.
+func NewLegacyObjectPoolV2(proto Object, maxLocalCacheSize int) *ObjectPoolV2[*LegacyObject] {
+	return NewObjectPoolV2(func() *LegacyObject {
+		return &LegacyObject{Object: proto.Instance()}
+	}, maxLocalCacheSize)
+}
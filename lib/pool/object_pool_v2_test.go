@@ -0,0 +1,138 @@
+// Copyright 2024 openGemini Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testObjectV2 struct {
+	n      int
+	resets int
+}
+
+func (o *testObjectV2) MemSize() int { return o.n }
+func (o *testObjectV2) Reset()       { o.resets++; o.n = 0 }
+
+func TestObjectPoolV2GetPut(t *testing.T) {
+	p := NewObjectPoolV2(func() *testObjectV2 { return &testObjectV2{} }, 1024)
+
+	obj := p.Get()
+	assert.NotNil(t, obj)
+	obj.n = 8
+	p.Put(obj)
+
+	obj2 := p.Get()
+	assert.NotNil(t, obj2)
+	assert.Equal(t, 0, obj2.n, "Put must Reset before the object is reused")
+}
+
+func TestObjectPoolV2DiscardsOversizedObjects(t *testing.T) {
+	var created int
+	p := NewObjectPoolV2(func() *testObjectV2 {
+		created++
+		return &testObjectV2{}
+	}, 4)
+
+	obj := p.Get()
+	obj.n = 100 // exceeds maxLocalCacheSize
+	p.Put(obj)
+
+	p.Get()
+	assert.Equal(t, 2, created, "an oversized object must not be reused")
+}
+
+func TestObjectPoolV2HitRatioHook(t *testing.T) {
+	var total, hit int64
+	p := NewObjectPoolV2(func() *testObjectV2 { return &testObjectV2{} }, 1024)
+	p.SetHitRatioHook(NewHitRatioHook(
+		func(n int64) { total += n },
+		func(n int64) { hit += n },
+	))
+
+	obj := p.Get()
+	p.Put(obj)
+	p.Get()
+
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, int64(1), hit)
+}
+
+func TestObjectPoolV2Concurrent(t *testing.T) {
+	p := NewObjectPoolV2(func() *testObjectV2 { return &testObjectV2{} }, 1024)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				obj := p.Get()
+				p.Put(obj)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type legacyTestObject struct {
+	n int
+}
+
+func (o *legacyTestObject) MemSize() int     { return o.n }
+func (o *legacyTestObject) Instance() Object { return &legacyTestObject{} }
+
+func TestLegacyObjectPoolV2(t *testing.T) {
+	p := NewLegacyObjectPoolV2(&legacyTestObject{}, 1024)
+
+	wrapper := p.Get()
+	assert.NotNil(t, wrapper)
+	wrapper.Object.(*legacyTestObject).n = 5
+	p.Put(wrapper)
+
+	wrapper2 := p.Get()
+	assert.Equal(t, 0, wrapper2.Object.(*legacyTestObject).n, "legacy Reset must swap in a fresh Instance")
+}
+
+type resettableLegacyObject struct {
+	n         int
+	resets    int
+	instances int
+}
+
+func (o *resettableLegacyObject) MemSize() int { return o.n }
+func (o *resettableLegacyObject) Instance() Object {
+	o.instances++
+	return &resettableLegacyObject{}
+}
+func (o *resettableLegacyObject) Reset() { o.resets++; o.n = 0 }
+
+func TestLegacyObjectPoolV2PrefersInPlaceReset(t *testing.T) {
+	obj := &resettableLegacyObject{}
+	p := NewLegacyObjectPoolV2(obj, 1024)
+
+	wrapper := p.Get()
+	wrapper.Object.(*resettableLegacyObject).n = 5
+	p.Put(wrapper)
+
+	wrapper2 := p.Get()
+	got := wrapper2.Object.(*resettableLegacyObject)
+	assert.Equal(t, 0, got.n)
+	assert.Equal(t, 1, got.resets, "Reset must use the wrapped Object's in-place Reset")
+	assert.Same(t, wrapper.Object, wrapper2.Object, "an in-place reset must not allocate a fresh Instance")
+}
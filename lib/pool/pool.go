@@ -15,15 +15,29 @@
 package pool
 
 import (
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/openGemini/openGemini/lib/bufferpool"
 	"github.com/openGemini/openGemini/lib/cpu"
 )
 
+// maxHookLatencySamples bounds the number of ObserveLatency samples a
+// HitRatioHook keeps for LatencyPercentile, so a long-running pool doesn't
+// grow the sample slice without bound.
+const maxHookLatencySamples = 256
+
 type HitRatioHook struct {
 	IncrTotal func(int64)
 	IncrHit   func(int64)
+
+	bytesIn  int64 // atomic
+	bytesOut int64 // atomic
+
+	latMu     sync.Mutex
+	latencies []time.Duration
 }
 
 func NewHitRatioHook(total, hit func(int64)) *HitRatioHook {
@@ -33,6 +47,51 @@ func NewHitRatioHook(total, hit func(int64)) *HitRatioHook {
 	}
 }
 
+// IncrBytes accumulates the uncompressed/compressed byte counts of one pool
+// operation, letting callers derive achieved throughput and compression
+// ratio alongside the existing hit-ratio tracking.
+func (h *HitRatioHook) IncrBytes(in, out int64) {
+	atomic.AddInt64(&h.bytesIn, in)
+	atomic.AddInt64(&h.bytesOut, out)
+}
+
+// Bytes returns the cumulative bytes passed to IncrBytes so far.
+func (h *HitRatioHook) Bytes() (in, out int64) {
+	return atomic.LoadInt64(&h.bytesIn), atomic.LoadInt64(&h.bytesOut)
+}
+
+// ObserveLatency records one operation's latency for later percentile
+// queries via LatencyPercentile. It keeps at most maxHookLatencySamples,
+// dropping the oldest once full.
+func (h *HitRatioHook) ObserveLatency(d time.Duration) {
+	h.latMu.Lock()
+	defer h.latMu.Unlock()
+
+	h.latencies = append(h.latencies, d)
+	if over := len(h.latencies) - maxHookLatencySamples; over > 0 {
+		h.latencies = h.latencies[over:]
+	}
+}
+
+// LatencyPercentile returns the p-th percentile (0 < p <= 100) of the
+// latencies currently retained by ObserveLatency (the most recent
+// maxHookLatencySamples), or 0 if nothing has been observed yet.
+func (h *HitRatioHook) LatencyPercentile(p float64) time.Duration {
+	h.latMu.Lock()
+	defer h.latMu.Unlock()
+
+	if len(h.latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), h.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(p / 100 * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
 type FixedPool struct {
 	pool chan interface{}
 	new  func() interface{}